@@ -0,0 +1,85 @@
+// Package campaign loads campaign.yaml, the ordered list of message parts
+// (text, media, vCard) sent to every recipient, and renders its text parts
+// against per-recipient template variables.
+package campaign
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PartType identifies what kind of message a Part produces.
+type PartType string
+
+const (
+	PartText     PartType = "text"
+	PartImage    PartType = "image"
+	PartVideo    PartType = "video"
+	PartDocument PartType = "document"
+	PartVCard    PartType = "vcard"
+)
+
+// Part is one item in a campaign's ordered send list.
+type Part struct {
+	Type PartType `yaml:"type"`
+
+	// Text is a Go text/template body, used by PartText and as the optional
+	// caption on PartImage/PartVideo/PartDocument.
+	Text string `yaml:"text,omitempty"`
+
+	// Path is the local file path of the attachment, used by
+	// PartImage/PartVideo/PartDocument.
+	Path string `yaml:"path,omitempty"`
+
+	// Filename overrides the displayed file name for PartDocument; if
+	// empty, the base name of Path is used.
+	Filename string `yaml:"filename,omitempty"`
+
+	// Name is the contact's display name, used by PartVCard.
+	Name string `yaml:"name,omitempty"`
+	// VCard is the raw vCard (text/vcard) payload, used by PartVCard.
+	VCard string `yaml:"vcard,omitempty"`
+}
+
+// Campaign is the parsed contents of campaign.yaml: the ordered parts sent
+// to every recipient.
+type Campaign struct {
+	Parts []Part `yaml:"parts"`
+}
+
+// Load reads and parses a campaign file in YAML (or JSON, which is valid
+// YAML) format.
+func Load(path string) (*Campaign, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read campaign file '%s': %w", path, err)
+	}
+
+	var c Campaign
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse campaign file '%s': %w", path, err)
+	}
+	if len(c.Parts) == 0 {
+		return nil, fmt.Errorf("campaign file '%s' has no parts", path)
+	}
+	return &c, nil
+}
+
+// Render executes text as a Go text/template against vars, so parts can
+// reference per-recipient placeholders such as {{.Name}} and {{.Number}}.
+func Render(text string, vars map[string]string) (string, error) {
+	tmpl, err := template.New("part").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %q: %w", text, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
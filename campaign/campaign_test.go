@@ -0,0 +1,59 @@
+package campaign
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "campaign.yaml")
+	if err := os.WriteFile(path, []byte("parts:\n  - type: text\n    text: \"hi {{.Name}}\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write test campaign: %v", err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(c.Parts) != 1 || c.Parts[0].Type != PartText {
+		t.Errorf("Load() = %+v, unexpected", c)
+	}
+}
+
+func TestLoadNoParts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "campaign.yaml")
+	if err := os.WriteFile(path, []byte("parts: []\n"), 0o644); err != nil {
+		t.Fatalf("failed to write test campaign: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Load should error when the campaign has no parts")
+	}
+}
+
+func TestRender(t *testing.T) {
+	got, err := Render("Hi {{.Name}}, your code is {{.Number}}", map[string]string{"Name": "Ada", "Number": "15551234567"})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if want := "Hi Ada, your code is 15551234567"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	got, err := Render("Hi {{.Name}}", map[string]string{})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if want := "Hi "; got != want {
+		t.Errorf("Render() with missing key = %q, want %q", got, want)
+	}
+}
+
+func TestRenderMalformed(t *testing.T) {
+	if _, err := Render("Hi {{.Name", map[string]string{"Name": "Ada"}); err == nil {
+		t.Error("Render should error on a malformed template")
+	}
+}
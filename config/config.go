@@ -0,0 +1,87 @@
+// Package config loads the optional wspReq.json settings file: the
+// permanent number blacklist and the jitter range applied between sends.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// defaultJitterMinMs and defaultJitterMaxMs bound the randomized pause
+// between successful sends when wspReq.json doesn't specify one.
+const (
+	defaultJitterMinMs = 1000
+	defaultJitterMaxMs = 4000
+)
+
+// Config is the contents of wspReq.json.
+type Config struct {
+	// BlackList holds recipient numbers whose numbers.txt items are recorded
+	// as blacklisted, and so never sent, when the campaign is enqueued. It is
+	// not consulted for API-submitted sends, nor for items already queued
+	// when a run skips enqueueing (--resume).
+	BlackList []string `json:"BlackList"`
+
+	// JitterMinMs and JitterMaxMs bound the random human-like delay
+	// inserted between successful sends.
+	JitterMinMs int `json:"JitterMinMs"`
+	JitterMaxMs int `json:"JitterMaxMs"`
+
+	// ProvisioningAddr is the listen address for the HTTP provisioning API
+	// (e.g. "127.0.0.1:29169"). Empty disables the API.
+	ProvisioningAddr string `json:"ProvisioningAddr"`
+	// ProvisioningToken is the bearer token required on every provisioning
+	// API request.
+	ProvisioningToken string `json:"ProvisioningToken"`
+
+	blacklist map[string]bool
+}
+
+// Load reads wspReq.json at path. A missing file is not an error; it
+// yields a Config with no blacklist and the default jitter range.
+func Load(path string) (*Config, error) {
+	c := &Config{JitterMinMs: defaultJitterMinMs, JitterMaxMs: defaultJitterMaxMs}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.index()
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read config file '%s': %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	if c.JitterMaxMs < c.JitterMinMs {
+		c.JitterMaxMs = c.JitterMinMs
+	}
+	c.index()
+	return c, nil
+}
+
+func (c *Config) index() {
+	c.blacklist = make(map[string]bool, len(c.BlackList))
+	for _, number := range c.BlackList {
+		c.blacklist[number] = true
+	}
+}
+
+// IsBlacklisted reports whether number is on the BlackList.
+func (c *Config) IsBlacklisted(number string) bool {
+	return c.blacklist[number]
+}
+
+// Jitter returns a random human-like pause in [JitterMinMs, JitterMaxMs].
+func (c *Config) Jitter() time.Duration {
+	if c.JitterMaxMs <= c.JitterMinMs {
+		return time.Duration(c.JitterMinMs) * time.Millisecond
+	}
+	span := c.JitterMaxMs - c.JitterMinMs
+	ms := c.JitterMinMs + rand.Intn(span+1)
+	return time.Duration(ms) * time.Millisecond
+}
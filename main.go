@@ -1,59 +1,50 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
 
-	"github.com/tebeka/selenium"
-	"github.com/tebeka/selenium/chrome"
+	"github.com/tugtagfatih/WhatsappBulkMessage/campaign"
+	"github.com/tugtagfatih/WhatsappBulkMessage/config"
+	"github.com/tugtagfatih/WhatsappBulkMessage/provisioning"
+	"github.com/tugtagfatih/WhatsappBulkMessage/queue"
+	"github.com/tugtagfatih/WhatsappBulkMessage/recipients"
+	"github.com/tugtagfatih/WhatsappBulkMessage/sender"
 )
 
 const (
-	// chromeDriverPath: Path to the ChromeDriver executable.
-	// If empty, chromedriver is expected to be in the system PATH.
-	chromeDriverPath = "chromedriver-win64\\chromedriver.exe" // IMPORTANT: Change this to your ChromeDriver path
-
-	// useProfile: Whether to use a Chrome profile.
-	useProfile = true
-
-	// profileFolder: Folder to store Chrome profile data.
-	profileFolder = "whatsapp_profile_go" // IMPORTANT: Change this to your desired profile path. Relative paths are relative to the executable.
-
-	// numbersFilePath: Name of the file containing phone numbers.
+	// numbersFilePath: Name of the CSV file listing recipients. The header
+	// row must include a "number" column; every other column becomes a
+	// template variable available to campaign.yaml's text parts.
 	numbersFilePath = "numbers.txt"
-	// messageFilePath: Name of the file containing the message to send.
-	messageFilePath = "text.txt"
+	// campaignFilePath: Name of the file describing what to send.
+	campaignFilePath = "campaign.yaml"
+	// configFilePath: Name of the optional blacklist/jitter/provisioning
+	// settings file.
+	configFilePath = "wspReq.json"
+	// queueDBPath: Name of the SQLite database backing the send queue.
+	queueDBPath = "queue.db"
 
 	// logsDir: Directory to store log files.
 	logsDir = "logs"
 
-	// whatsAppURL: WhatsApp Web address.
-	whatsAppURL = "https://web.whatsapp.com/"
-	// seleniumPort: Port for ChromeDriver to run on.
-	seleniumPort = 9515 // Common port for ChromeDriver, change if needed.
+	// servePollInterval is how often the worker loop checks for new
+	// API-submitted items while running as a provisioning service.
+	servePollInterval = 2 * time.Second
 )
 
-// readLines reads a file line by line and returns the lines as a slice.
-func readLines(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, strings.TrimSpace(scanner.Text()))
-	}
-	return lines, scanner.Err()
-}
+var (
+	resume      = flag.Bool("resume", false, "don't enqueue from numbers.txt/campaign.yaml; just keep draining whatever is already queued")
+	retryFailed = flag.Bool("retry-failed", false, "reset items that permanently failed back to pending before sending")
+	dryRun      = flag.Bool("dry-run", false, "log what would be sent without connecting to WhatsApp or touching the queue")
+	serve       = flag.Bool("serve", false, "run the HTTP provisioning API and keep processing queued sends until interrupted")
+	listGroups  = flag.Bool("list-groups", false, "connect, print every joined group's JID and subject, and exit")
+)
 
 // setupLogFile creates the logs directory if it doesn't exist and creates a new log file with a timestamp.
 // It returns the file pointer and an error if any.
@@ -88,7 +79,51 @@ func logMessage(logFile *os.File, format string, args ...interface{}) {
 	}
 }
 
+// sendPart dispatches part, with its already-rendered template text, to the
+// matching sender.Client method.
+func sendPart(client *sender.Client, number string, part campaign.Part, text string) (string, error) {
+	switch part.Type {
+	case campaign.PartText:
+		return client.SendText(number, text)
+	case campaign.PartImage:
+		return client.SendImage(number, part.Path, text)
+	case campaign.PartVideo:
+		return client.SendVideo(number, part.Path, text)
+	case campaign.PartDocument:
+		return client.SendDocument(number, part.Path, part.Filename, text)
+	case campaign.PartVCard:
+		return client.SendContact(number, part.Name, part.VCard)
+	default:
+		return "", fmt.Errorf("unknown part type '%s'", part.Type)
+	}
+}
+
+// sendQueueItem dispatches a single due queue item, whether it came from
+// numbers.txt/campaign.yaml or the provisioning API's POST /send.
+func sendQueueItem(client *sender.Client, item queue.Item, recipientByNumber map[string]recipients.Recipient, camp *campaign.Campaign) (string, error) {
+	if item.Source == queue.SourceAPI {
+		if item.MediaURL != "" {
+			return client.SendDocumentFromURL(item.Number, item.MediaURL, "", item.Text)
+		}
+		return client.SendText(item.Number, item.Text)
+	}
+
+	r, ok := recipientByNumber[item.Number]
+	if !ok || item.PartIndex >= len(camp.Parts) {
+		return "", fmt.Errorf("%s part %d no longer matches numbers.txt/campaign.yaml", item.Number, item.PartIndex)
+	}
+	part := camp.Parts[item.PartIndex]
+
+	text, err := campaign.Render(part.Text, r.Vars)
+	if err != nil {
+		return "", err
+	}
+	return sendPart(client, item.Number, part, text)
+}
+
 func main() {
+	flag.Parse()
+
 	// Setup logging
 	logFile, err := setupLogFile()
 	if err != nil {
@@ -97,207 +132,208 @@ func main() {
 	defer logFile.Close()
 
 	logMessage(logFile, "Application started.\n")
-	var service *selenium.Service
 
-	if chromeDriverPath != "" {
-		service, err = selenium.NewChromeDriverService(chromeDriverPath, seleniumPort)
-		if err != nil {
-			logMessage(logFile, "Failed to start ChromeDriver service from specified path ('%s'): %v. Trying ChromeDriver from PATH.\n", chromeDriverPath, err)
-			service, err = selenium.NewChromeDriverService("", seleniumPort)
-		}
-	} else {
-		service, err = selenium.NewChromeDriverService("", seleniumPort)
+	logger := func(format string, args ...interface{}) {
+		logMessage(logFile, format, args...)
 	}
 
+	cfg, err := config.Load(configFilePath)
 	if err != nil {
-		logMessage(logFile, "Error starting ChromeDriver service (tried specified path and PATH): %v\n", err)
+		logMessage(logFile, "Error reading config from '%s': %v\n", configFilePath, err)
 		os.Exit(1)
 	}
-	defer service.Stop()
-
-	// 2. Setup Chrome Capabilities
-	caps := selenium.Capabilities{"browserName": "chrome"}
-	chromeCaps := chrome.Capabilities{}
 
-	if useProfile {
-		// Ensure profile folder exists
-		absProfileFolder, err := filepath.Abs(profileFolder)
+	if *listGroups {
+		client, err := sender.NewClient(logger)
 		if err != nil {
-			logMessage(logFile, "Could not get absolute path for profile folder ('%s'): %v\n", profileFolder, err)
+			logMessage(logFile, "Error connecting to WhatsApp: %v\n", err)
 			os.Exit(1)
 		}
+		defer client.Close()
 
-		if _, statErr := os.Stat(absProfileFolder); os.IsNotExist(statErr) {
-			if mkdirErr := os.MkdirAll(absProfileFolder, 0755); mkdirErr != nil {
-				logMessage(logFile, "Could not create profile folder ('%s'): %v\n", absProfileFolder, mkdirErr)
-				os.Exit(1)
-			}
-			//logMessage(logFile, "Profile folder created: %s\n", absProfileFolder)
-		} else if statErr != nil {
-			logMessage(logFile, "Error checking profile folder ('%s'): %v\n", absProfileFolder, statErr)
+		groups, err := client.ListGroups()
+		if err != nil {
+			logMessage(logFile, "Error listing groups: %v\n", err)
 			os.Exit(1)
 		}
-		chromeCaps.Args = append(chromeCaps.Args, fmt.Sprintf("user-data-dir=%s", absProfileFolder))
+		for _, g := range groups {
+			fmt.Printf("%s\t%s\n", g.JID, g.Name)
+		}
+		return
 	}
-	caps.AddChrome(chromeCaps)
 
-	// 3. Connect to WebDriver
-	wd, err := selenium.NewRemote(caps, fmt.Sprintf("http://localhost:%d/wd/hub", seleniumPort))
-	if err != nil {
-		logMessage(logFile, "Error connecting to WebDriver: %v\n", err)
-		os.Exit(1)
-	}
-	defer wd.Quit()
+	// --serve runs purely off the provisioning API's POST /send: it has no
+	// use for numbers.txt/campaign.yaml, so neither is loaded, and nothing
+	// gets enqueued from files.
+	var (
+		recipientList     []recipients.Recipient
+		recipientByNumber map[string]recipients.Recipient
+		camp              *campaign.Campaign
+	)
+	if !*serve {
+		recipientList, err = recipients.Load(numbersFilePath)
+		if err != nil {
+			logMessage(logFile, "Error reading recipients from '%s': %v\n", numbersFilePath, err)
+			os.Exit(1)
+		}
+		if len(recipientList) == 0 {
+			logMessage(logFile, "No recipients found in '%s'. Please add rows to the file.\n", numbersFilePath)
+			os.Exit(1)
+		}
+		recipientByNumber = make(map[string]recipients.Recipient, len(recipientList))
+		for _, r := range recipientList {
+			recipientByNumber[r.Number] = r
+		}
 
-	// 4. Navigate to WhatsApp Web and Login
-	if err := wd.Get(whatsAppURL); err != nil {
-		logMessage(logFile, "Error opening WhatsApp Web ('%s'): %v\n", whatsAppURL, err)
-		os.Exit(1)
+		camp, err = campaign.Load(campaignFilePath)
+		if err != nil {
+			logMessage(logFile, "Error reading campaign from '%s': %v\n", campaignFilePath, err)
+			os.Exit(1)
+		}
 	}
 
-	loginCheckSelector := "div[role='textbox']"
-	//logMessage(logFile, "Please scan the QR code if prompted. Waiting for login (max 120 seconds for '%s' element to appear)...\n", loginCheckSelector)
-
-	err = wd.WaitWithTimeoutAndInterval(func(wd selenium.WebDriver) (bool, error) {
-		_, findErr := wd.FindElement(selenium.ByCSSSelector, loginCheckSelector)
-		if findErr == nil {
-			logMessage(logFile, "Login check element found.\n")
-			return true, nil
+	// --dry-run only previews what a real run would enqueue and send; it
+	// never opens the queue database, so it can't leave stale pending rows
+	// behind for a later real run to pick up.
+	if *dryRun {
+		for _, r := range recipientList {
+			for partIndex, part := range camp.Parts {
+				text, err := campaign.Render(part.Text, r.Vars)
+				if err != nil {
+					logMessage(logFile, "Error rendering part %d for %s: %v\n", partIndex, r.Number, err)
+					os.Exit(1)
+				}
+				if cfg.IsBlacklisted(r.Number) {
+					logMessage(logFile, "[dry-run] would skip %s part %d (type=%s): blacklisted\n", r.Number, partIndex, part.Type)
+					continue
+				}
+				logMessage(logFile, "[dry-run] would send %s part %d (type=%s): %s\n", r.Number, partIndex, part.Type, text)
+			}
 		}
-		return false, nil
-	}, 120*time.Second, 1*time.Second)
+		logMessage(logFile, "Dry run complete.\n")
+		return
+	}
 
+	q, err := queue.Open(queueDBPath)
 	if err != nil {
-		logMessage(logFile, "Login failed or timed out after 120 seconds! Error: %v. Please ensure you are logged into WhatsApp Web and the selector '%s' is correct.\n", err, loginCheckSelector)
+		logMessage(logFile, "Error opening queue: %v\n", err)
 		os.Exit(1)
 	}
-	//logMessage(logFile, "Successfully logged into WhatsApp Web!\n")
-	time.Sleep(3 * time.Second)
+	defer q.Close()
 
-	// 5. Read numbers from numbers.txt
-	numbers, err := readLines(numbersFilePath)
-	if err != nil {
-		logMessage(logFile, "Error reading numbers from '%s': %v\n", numbersFilePath, err)
-		os.Exit(1)
+	if *retryFailed {
+		n, err := q.RequeueFailed()
+		if err != nil {
+			logMessage(logFile, "Error requeueing failed items: %v\n", err)
+			os.Exit(1)
+		}
+		logMessage(logFile, "Requeued %d permanently failed item(s) for retry.\n", n)
 	}
-	if len(numbers) == 0 {
-		logMessage(logFile, "No numbers found in '%s'. Please add phone numbers to the file.\n", numbersFilePath)
-		os.Exit(1)
+
+	if *serve {
+		logMessage(logFile, "Serving: draining whatever is queued, fed only by the provisioning API.\n")
+	} else if !*resume {
+		for _, r := range recipientList {
+			for partIndex, part := range camp.Parts {
+				text, err := campaign.Render(part.Text, r.Vars)
+				if err != nil {
+					logMessage(logFile, "Error rendering part %d for %s: %v\n", partIndex, r.Number, err)
+					os.Exit(1)
+				}
+				hash := queue.MessageHash(strconv.Itoa(partIndex), string(part.Type), text, part.Path, part.Filename, part.Name, part.VCard)
+
+				if cfg.IsBlacklisted(r.Number) {
+					if err := q.EnqueueBlacklisted(r.Number, hash, partIndex); err != nil {
+						logMessage(logFile, "Error recording blacklisted %s: %v\n", r.Number, err)
+						os.Exit(1)
+					}
+					continue
+				}
+				if err := q.Enqueue(r.Number, hash, partIndex); err != nil {
+					logMessage(logFile, "Error enqueueing %s part %d: %v\n", r.Number, partIndex, err)
+					os.Exit(1)
+				}
+			}
+		}
+	} else {
+		logMessage(logFile, "Resuming: skipping re-enqueue, draining the existing queue.\n")
 	}
-	//logMessage(logFile, "%d numbers found to process.\n", len(numbers))
 
-	// 6. Read message from text.txt
-	messageBytes, err := os.ReadFile(messageFilePath)
-	if err != nil {
-		logMessage(logFile, "Error reading message from '%s': %v\n", messageFilePath, err)
-		os.Exit(1)
+	// Connect to WhatsApp. In --serve mode we never block on a terminal QR
+	// code; pairing instead happens through the provisioning API's
+	// /login websocket so the process can run headless.
+	var client *sender.Client
+	if *serve {
+		client, err = sender.NewUnpaired(logger)
+	} else {
+		client, err = sender.NewClient(logger)
 	}
-	rawMessage := strings.TrimSpace(string(messageBytes))
-	if rawMessage == "" {
-		logMessage(logFile, "Message in '%s' is empty. Please write a message to send.\n", messageFilePath)
+	if err != nil {
+		logMessage(logFile, "Error connecting to WhatsApp: %v\n", err)
 		os.Exit(1)
 	}
-	encodedMessage := url.QueryEscape(rawMessage)
-	// logMessage(logFile, "Message to send (raw): %s\n", rawMessage)
-
-	// 7. Send Messages
-	sendButtonSelectors := []struct {
-		Type string
-		Path string
-	}{
-		{selenium.ByXPATH, "//span[@data-icon='wds-ic-send-filled']"},
-		{selenium.ByXPATH, "//button[@aria-label='Send']"},
-		{selenium.ByXPATH, "//button[@aria-label='GÃ¶nder']"},
-		{selenium.ByCSSSelector, "span[data-testid='send']"},
-		{selenium.ByCSSSelector, "button[data-testid='send']"},
-	}
-	messageBoxSelector := "div[data-testid='conversation-compose-box-input']"
+	defer client.Close()
 
-	for _, number := range numbers {
-		if number == "" {
-			continue
+	if *serve {
+		if cfg.ProvisioningAddr == "" {
+			logMessage(logFile, "--serve requires ProvisioningAddr to be set in '%s'.\n", configFilePath)
+			os.Exit(1)
 		}
-		//logMessage(logFile, "\nProcessing number: %s\n", number)
+		srv := provisioning.New(cfg.ProvisioningAddr, cfg.ProvisioningToken, client, q, logger)
+		go func() {
+			if err := srv.ListenAndServe(); err != nil {
+				logMessage(logFile, "Provisioning API stopped: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
 
-		sendURL := fmt.Sprintf("https://web.whatsapp.com/send?phone=%s&text=%s", number, encodedMessage)
-		if err := wd.Get(sendURL); err != nil {
-			logMessage(logFile, "FAILED to navigate to send URL for %s: %v\n", number, err)
-			time.Sleep(2 * time.Second)
-			continue
+	for {
+		due, err := q.Due(time.Now())
+		if err != nil {
+			logMessage(logFile, "Error reading queue: %v\n", err)
+			os.Exit(1)
 		}
 
-		var sendButton selenium.WebElement
-		buttonFound := false
-		waitStartTime := time.Now()
-
-		for time.Since(waitStartTime) < 30*time.Second {
-			for _, selector := range sendButtonSelectors {
-				element, findErr := wd.FindElement(selector.Type, selector.Path)
-				if findErr == nil {
-					displayed, _ := element.IsDisplayed()
-					enabled, _ := element.IsEnabled()
-					if displayed && enabled {
-						sendButton = element
-						buttonFound = true
-						break
-					}
-				}
+		if len(due) == 0 {
+			if *serve {
+				time.Sleep(servePollInterval)
+				continue
 			}
-			if buttonFound {
+			nextAt, ok, err := q.NextPendingAt()
+			if err != nil {
+				logMessage(logFile, "Error reading queue: %v\n", err)
+				os.Exit(1)
+			}
+			if !ok {
 				break
 			}
-			time.Sleep(500 * time.Millisecond)
-		}
-
-		if !buttonFound {
-			//logMessage(logFile, "FAILED: Send button for %s was not clickable within 30 seconds. Attempting to press Enter in message box.\n", number)
-			msgBox, findErr := wd.FindElement(selenium.ByCSSSelector, messageBoxSelector)
-			if findErr == nil {
-				displayed, _ := msgBox.IsDisplayed()
-				if displayed {
-					//logMessage(logFile, "Message box found, sending Enter key for %s...\n", number)
-					if errEnter := msgBox.SendKeys(selenium.EnterKey); errEnter == nil {
-						//logMessage(logFile, "SUCCESS (probably): Message sent to %s by pressing Enter in message box.\n", number)
-						time.Sleep(5 * time.Second)
-						continue
-					} else {
-						//logMessage(logFile, "FAILED: Could not send Enter key to message box for %s: %v\n", number, errEnter)
-					}
-				} else {
-					//logMessage(logFile, "FAILED: Message box found for %s but not displayed.\n", number)
-				}
-			} else {
-				//logMessage(logFile, "FAILED: Message box ('%s') to press Enter not found for %s: %v\n", number, messageBoxSelector, findErr)
+			if wait := time.Until(nextAt); wait > 0 {
+				logMessage(logFile, "Waiting %s before the next retry is due...\n", wait.Round(time.Second))
+				time.Sleep(wait)
 			}
-			logMessage(logFile, "Skipping %s as message could not be sent.\n", number)
-			time.Sleep(2 * time.Second)
 			continue
 		}
 
-		if errClick := sendButton.Click(); errClick != nil {
-			//logMessage(logFile, "FAILED: Error clicking send button for %s: %v. Attempting fallback Enter key.\n", number, errClick)
-			msgBox, findErr := wd.FindElement(selenium.ByCSSSelector, messageBoxSelector)
-			if findErr == nil {
-				if errEnter := msgBox.SendKeys(selenium.EnterKey); errEnter == nil {
-					logMessage(logFile, "SUCCESS (probably): Message sent to %s via Enter key after click failed.\n", number)
-					time.Sleep(5 * time.Second)
-					continue
-				} else {
-					logMessage(logFile, "FAILED: Fallback Enter key also failed to send to message box for %s: %v\n", number, errEnter)
+		for _, item := range due {
+			msgID, sendErr := sendQueueItem(client, item, recipientByNumber, camp)
+			if sendErr != nil {
+				if err := q.MarkFailed(item.ID, sendErr); err != nil {
+					logMessage(logFile, "Error recording failure for %s part %d: %v\n", item.Number, item.PartIndex, err)
 				}
+				logMessage(logFile, "FAILED: %s part %d (source=%s): %v\n", item.Number, item.PartIndex, item.Source, sendErr)
+				continue
 			}
-			logMessage(logFile, "Skipping %s due to persistent send error.\n", number)
-			time.Sleep(2 * time.Second)
-			continue
-		}
 
-		logMessage(logFile, "SUCCESS: Message sent to: %s\n", number)
-		time.Sleep(5 * time.Second)
+			if err := q.MarkSent(item.ID); err != nil {
+				logMessage(logFile, "Error recording success for %s part %d: %v\n", item.Number, item.PartIndex, err)
+			}
+			logMessage(logFile, "SUCCESS: %s part %d (source=%s) sent (id=%s)\n", item.Number, item.PartIndex, item.Source, msgID)
+
+			time.Sleep(cfg.Jitter())
+		}
 	}
 
-	logMessage(logFile, "\nAll message sending attempts completed.\n")
-	logMessage(logFile, "Please check WhatsApp Web to confirm sent messages and the log file for details.\n")
-	logMessage(logFile, "Browser window will close in 10 seconds...\n")
-	time.Sleep(10 * time.Second)
+	logMessage(logFile, "\nAll queued messages processed.\n")
 	logMessage(logFile, "Application finished.\n")
 }
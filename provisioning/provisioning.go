@@ -0,0 +1,226 @@
+// Package provisioning exposes an authenticated HTTP API, modeled after
+// mautrix-whatsapp's provisioning API, so other apps (schedulers, CRMs) can
+// drive this tool as a headless service instead of a one-shot CLI: pair a
+// session, check connection status, and submit sends that share the same
+// queue and worker as file-submitted campaigns.
+package provisioning
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/tugtagfatih/WhatsappBulkMessage/queue"
+	"github.com/tugtagfatih/WhatsappBulkMessage/sender"
+)
+
+// Server is the HTTP provisioning API. Create one with New and run it with
+// ListenAndServe.
+type Server struct {
+	addr   string
+	token  string
+	client *sender.Client
+	queue  *queue.Queue
+	log    sender.Logger
+
+	upgrader websocket.Upgrader
+}
+
+// New returns a provisioning Server that authenticates requests against
+// token and drives client/q for pairing and sends.
+func New(addr, token string, client *sender.Client, q *queue.Queue, log sender.Logger) *Server {
+	return &Server{
+		addr:   addr,
+		token:  token,
+		client: client,
+		queue:  q,
+		log:    log,
+	}
+}
+
+// ListenAndServe registers the provisioning routes and serves them on
+// s.addr until the process exits or an unrecoverable error occurs.
+func (s *Server) ListenAndServe() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", s.handleLogin)
+	mux.HandleFunc("/status", s.authenticated(s.handleStatus))
+	mux.HandleFunc("/send", s.authenticated(s.handleSend))
+	mux.HandleFunc("/jobs/", s.authenticated(s.handleJob))
+
+	s.log("Provisioning API listening on %s\n", s.addr)
+	return http.ListenAndServe(s.addr, mux)
+}
+
+// authenticated wraps next with a check for "Authorization: Bearer <token>".
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.tokenMatches(r) {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// tokenMatches reports whether r carries the configured bearer token,
+// compared in constant time so the check isn't a timing side-channel.
+func (s *Server) tokenMatches(r *http.Request) bool {
+	if s.token == "" {
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+s.token)) == 1
+}
+
+// loginEvent is one message sent down the /login websocket.
+type loginEvent struct {
+	Type  string `json:"type"` // "code", "success", "error", or another whatsmeow QR event name
+	Text  string `json:"text,omitempty"`
+	PNG   string `json:"png,omitempty"` // base64-encoded PNG, set with Text
+	JID   string `json:"jid,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleLogin upgrades to a WebSocket and streams QR pairing events:
+// a "code" event per refreshed QR code (PNG + text), then "success" once
+// the phone confirms pairing. If a device is already paired it sends a
+// single "success" event immediately.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.tokenMatches(r) {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.log("Provisioning: failed to upgrade /login websocket: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	qrChan, err := s.client.PairViaChannel(r.Context())
+	if err != nil {
+		conn.WriteJSON(loginEvent{Type: "error", Error: err.Error()})
+		return
+	}
+	if qrChan == nil {
+		conn.WriteJSON(loginEvent{Type: "success", JID: s.client.JID().String()})
+		return
+	}
+
+	for evt := range qrChan {
+		switch evt.Event {
+		case "code":
+			png, err := qrcode.Encode(evt.Code, qrcode.Medium, 256)
+			if err != nil {
+				conn.WriteJSON(loginEvent{Type: "error", Error: err.Error()})
+				continue
+			}
+			conn.WriteJSON(loginEvent{Type: "code", Text: evt.Code, PNG: base64.StdEncoding.EncodeToString(png)})
+		case "success":
+			conn.WriteJSON(loginEvent{Type: "success", JID: s.client.JID().String()})
+		default:
+			conn.WriteJSON(loginEvent{Type: evt.Event})
+		}
+	}
+}
+
+// statusResponse is the body of GET /status.
+type statusResponse struct {
+	Connected bool   `json:"connected"`
+	JID       string `json:"jid,omitempty"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, statusResponse{
+		Connected: s.client.IsConnected(),
+		JID:       s.client.JID().String(),
+	})
+}
+
+// sendRequest is the body of POST /send.
+type sendRequest struct {
+	To       string `json:"to"`
+	Text     string `json:"text"`
+	MediaURL string `json:"media_url,omitempty"`
+}
+
+// sendResponse is the body returned by POST /send.
+type sendResponse struct {
+	JobID int64 `json:"job_id"`
+}
+
+// handleSend enqueues an API-submitted message onto the shared send queue
+// and returns a job id; the same worker loop that drains file-submitted
+// campaigns picks it up and actually sends it.
+func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req sendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.To == "" {
+		http.Error(w, `{"error":"'to' is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	hash := queue.MessageHash(req.To, req.Text, req.MediaURL)
+	jobID, err := s.queue.EnqueueAPI(req.To, hash, req.Text, req.MediaURL)
+	if err != nil {
+		s.log("Provisioning: failed to enqueue send to %s: %v\n", req.To, err)
+		http.Error(w, `{"error":"failed to enqueue"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, sendResponse{JobID: jobID})
+}
+
+// jobResponse is the body returned by GET /jobs/{id}.
+type jobResponse struct {
+	ID        int64  `json:"id"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"invalid job id"}`, http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.queue.Get(id)
+	if err != nil {
+		http.Error(w, `{"error":"job not found"}`, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobResponse{
+		ID:        item.ID,
+		Status:    string(item.Status),
+		Attempts:  item.Attempts,
+		LastError: item.LastError,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Fprintf(w, `{"error":"failed to encode response"}`)
+	}
+}
@@ -0,0 +1,286 @@
+// Package queue persists every (recipient, message) send as a row in a
+// local SQLite database, so the bulk send can be interrupted and resumed
+// without re-sending anything that already went out, and so failed sends
+// get retried with backoff instead of silently dropped.
+package queue
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// MessageHash derives a stable content identifier from parts, used as the
+// uniqueness key alongside a recipient number so the same message is never
+// queued twice for the same person.
+func MessageHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Status is the lifecycle state of one queued item.
+type Status string
+
+const (
+	StatusPending     Status = "pending"
+	StatusSent        Status = "sent"
+	StatusFailed      Status = "failed"
+	StatusBlacklisted Status = "blacklisted"
+)
+
+const (
+	// MaxAttempts is how many send attempts a failing item gets before it
+	// is parked as StatusFailed for good.
+	MaxAttempts = 5
+
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 5 * time.Minute
+)
+
+// BackoffFor returns how long to wait before the next attempt given that
+// attempt send attempts have already been made: 2s, 8s, 32s, ... capped at
+// maxBackoff.
+func BackoffFor(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 4
+		if d > maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// Source identifies where a queued item came from.
+type Source string
+
+const (
+	// SourceFile items reference a part of campaign.yaml by PartIndex.
+	SourceFile Source = "file"
+	// SourceAPI items carry their own content (Text/MediaURL), submitted
+	// through the provisioning HTTP API's POST /send endpoint.
+	SourceAPI Source = "api"
+)
+
+// apiPartIndex is stored in part_index for SourceAPI items, which have no
+// campaign part to reference.
+const apiPartIndex = -1
+
+// Item is one queued send: either a (recipient, campaign part) pair read
+// from numbers.txt/campaign.yaml, or a one-off API-submitted message.
+type Item struct {
+	ID            int64
+	Number        string
+	Source        Source
+	PartIndex     int
+	Text          string
+	MediaURL      string
+	MessageHash   string
+	Status        Status
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// Queue is a handle to the send-queue database.
+type Queue struct {
+	db *sql.DB
+}
+
+// Open creates (if needed) and opens the SQLite database at path.
+func Open(path string) (*Queue, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue database '%s': %w", path, err)
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS queue (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	number          TEXT    NOT NULL,
+	source          TEXT    NOT NULL DEFAULT 'file',
+	part_index      INTEGER NOT NULL,
+	text            TEXT    NOT NULL DEFAULT '',
+	media_url       TEXT    NOT NULL DEFAULT '',
+	message_hash    TEXT    NOT NULL,
+	status          TEXT    NOT NULL DEFAULT 'pending',
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT    NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS queue_file_number_hash ON queue (number, message_hash) WHERE source = 'file';`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize queue schema: %w", err)
+	}
+
+	return &Queue{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// Enqueue inserts a pending file-sourced item for (number, messageHash,
+// partIndex) if one doesn't already exist; it is a no-op otherwise, so
+// re-running against the same recipients and campaign is always safe.
+func (q *Queue) Enqueue(number, messageHash string, partIndex int) error {
+	_, err := q.db.Exec(
+		`INSERT OR IGNORE INTO queue (number, source, part_index, message_hash, status, next_attempt_at) VALUES (?, ?, ?, ?, ?, 0)`,
+		number, SourceFile, partIndex, messageHash, StatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s part %d: %w", number, partIndex, err)
+	}
+	return nil
+}
+
+// EnqueueBlacklisted records (number, messageHash, partIndex) as permanently
+// blacklisted, so it is never picked up by Due. An existing pending or
+// failed row for (number, messageHash) is switched to blacklisted; a sent
+// row is left as it is.
+func (q *Queue) EnqueueBlacklisted(number, messageHash string, partIndex int) error {
+	_, err := q.db.Exec(
+		`INSERT INTO queue (number, source, part_index, message_hash, status, next_attempt_at) VALUES (?, ?, ?, ?, ?, 0)
+		 ON CONFLICT (number, message_hash) WHERE source = 'file' DO UPDATE SET status = excluded.status WHERE status IN (?, ?)`,
+		number, SourceFile, partIndex, messageHash, StatusBlacklisted, StatusPending, StatusFailed,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue blacklisted %s part %d: %w", number, partIndex, err)
+	}
+	return nil
+}
+
+// EnqueueAPI inserts a pending API-sourced item carrying its own content,
+// submitted through the provisioning HTTP API's POST /send endpoint. Unlike
+// Enqueue/EnqueueBlacklisted, repeated calls with the same (number,
+// messageHash) always insert a new row rather than being ignored, since
+// API callers may legitimately resend identical content (e.g. a CRM
+// re-triggering the same reminder). It returns the new item's ID, to be
+// handed back to the caller as a job id.
+func (q *Queue) EnqueueAPI(number, messageHash, text, mediaURL string) (int64, error) {
+	res, err := q.db.Exec(
+		`INSERT INTO queue (number, source, part_index, text, media_url, message_hash, status, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0)`,
+		number, SourceAPI, apiPartIndex, text, mediaURL, messageHash, StatusPending,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue API send to %s: %w", number, err)
+	}
+	return res.LastInsertId()
+}
+
+// Get returns a single item by id, for the provisioning API's GET
+// /jobs/{id}.
+func (q *Queue) Get(id int64) (Item, error) {
+	row := q.db.QueryRow(
+		`SELECT id, number, source, part_index, text, media_url, message_hash, status, attempts, next_attempt_at, last_error
+		 FROM queue WHERE id = ?`,
+		id,
+	)
+	return scanItem(row)
+}
+
+// Due returns pending items whose next attempt is due, oldest first.
+func (q *Queue) Due(now time.Time) ([]Item, error) {
+	rows, err := q.db.Query(
+		`SELECT id, number, source, part_index, text, media_url, message_hash, status, attempts, next_attempt_at, last_error
+		 FROM queue WHERE status = ? AND next_attempt_at <= ? ORDER BY id`,
+		StatusPending, now.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due items: %w", err)
+	}
+	defer rows.Close()
+	return scanItems(rows)
+}
+
+// NextPendingAt returns the earliest next_attempt_at among all pending
+// items (due or not), so the caller can sleep until there's work to do. ok
+// is false if no pending items remain at all.
+func (q *Queue) NextPendingAt() (at time.Time, ok bool, err error) {
+	var unix sql.NullInt64
+	err = q.db.QueryRow(`SELECT MIN(next_attempt_at) FROM queue WHERE status = ?`, StatusPending).Scan(&unix)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to query next pending item: %w", err)
+	}
+	if !unix.Valid {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(unix.Int64, 0), true, nil
+}
+
+// MarkSent marks item as successfully sent.
+func (q *Queue) MarkSent(id int64) error {
+	_, err := q.db.Exec(`UPDATE queue SET status = ?, last_error = '' WHERE id = ?`, StatusSent, id)
+	return err
+}
+
+// MarkFailed records a failed attempt. If attempts have reached MaxAttempts
+// the item is parked as StatusFailed; otherwise it goes back to pending
+// with its next attempt scheduled after the backoff for the new attempt
+// count.
+func (q *Queue) MarkFailed(id int64, sendErr error) error {
+	var attempts int
+	if err := q.db.QueryRow(`SELECT attempts FROM queue WHERE id = ?`, id).Scan(&attempts); err != nil {
+		return fmt.Errorf("failed to read attempts for item %d: %w", id, err)
+	}
+	attempts++
+
+	status := StatusPending
+	nextAttempt := time.Now().Add(BackoffFor(attempts))
+	if attempts >= MaxAttempts {
+		status = StatusFailed
+	}
+
+	_, err := q.db.Exec(
+		`UPDATE queue SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		status, attempts, nextAttempt.Unix(), sendErr.Error(), id,
+	)
+	return err
+}
+
+// RequeueFailed resets every StatusFailed item back to StatusPending with a
+// fresh attempt budget, for --retry-failed.
+func (q *Queue) RequeueFailed() (int64, error) {
+	res, err := q.db.Exec(
+		`UPDATE queue SET status = ?, attempts = 0, next_attempt_at = 0, last_error = '' WHERE status = ?`,
+		StatusPending, StatusFailed,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue failed items: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanItem(s scanner) (Item, error) {
+	var it Item
+	var nextAttemptUnix int64
+	if err := s.Scan(&it.ID, &it.Number, &it.Source, &it.PartIndex, &it.Text, &it.MediaURL, &it.MessageHash, &it.Status, &it.Attempts, &nextAttemptUnix, &it.LastError); err != nil {
+		return Item{}, fmt.Errorf("failed to scan queue row: %w", err)
+	}
+	it.NextAttemptAt = time.Unix(nextAttemptUnix, 0)
+	return it, nil
+}
+
+func scanItems(rows *sql.Rows) ([]Item, error) {
+	var items []Item
+	for rows.Next() {
+		it, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackoffFor(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 2 * time.Second},
+		{attempt: 2, want: 8 * time.Second},
+		{attempt: 3, want: 32 * time.Second},
+		{attempt: 4, want: 128 * time.Second},
+		{attempt: 5, want: 5 * time.Minute},  // would be 512s uncapped
+		{attempt: 10, want: 5 * time.Minute}, // stays capped well past MaxAttempts
+	}
+
+	for _, c := range cases {
+		if got := BackoffFor(c.attempt); got != c.want {
+			t.Errorf("BackoffFor(%d) = %v, want %v", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestMessageHash(t *testing.T) {
+	if MessageHash("a", "b") != MessageHash("a", "b") {
+		t.Error("MessageHash is not deterministic for identical input")
+	}
+	if MessageHash("a", "b") == MessageHash("a", "c") {
+		t.Error("MessageHash collided for different input")
+	}
+}
+
+func TestEnqueueAPIAllowsRepeatedSends(t *testing.T) {
+	q, err := Open(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer q.Close()
+
+	hash := MessageHash("123", "reminder", "")
+	if _, err := q.EnqueueAPI("123", hash, "reminder", ""); err != nil {
+		t.Fatalf("first EnqueueAPI returned error: %v", err)
+	}
+	if _, err := q.EnqueueAPI("123", hash, "reminder", ""); err != nil {
+		t.Fatalf("repeated EnqueueAPI with identical content should succeed, got: %v", err)
+	}
+
+	due, err := q.Due(time.Now())
+	if err != nil {
+		t.Fatalf("Due returned error: %v", err)
+	}
+	if len(due) != 2 {
+		t.Errorf("Due returned %d items, want 2", len(due))
+	}
+}
+
+func TestEnqueueStillDeduplicatesFileItems(t *testing.T) {
+	q, err := Open(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer q.Close()
+
+	hash := MessageHash("123", "text", "hello")
+	if err := q.Enqueue("123", hash, 0); err != nil {
+		t.Fatalf("first Enqueue returned error: %v", err)
+	}
+	if err := q.Enqueue("123", hash, 0); err != nil {
+		t.Fatalf("repeated Enqueue for the same file item should be a no-op, got: %v", err)
+	}
+
+	due, err := q.Due(time.Now())
+	if err != nil {
+		t.Fatalf("Due returned error: %v", err)
+	}
+	if len(due) != 1 {
+		t.Errorf("Due returned %d items, want 1 (re-enqueue must stay idempotent)", len(due))
+	}
+}
@@ -0,0 +1,78 @@
+// Package recipients loads the CSV recipient list. The header row names the
+// template variables available to a campaign's text parts; every other
+// column becomes a per-recipient value keyed by that name.
+package recipients
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// numberColumn is the required header naming the recipient's phone number.
+const numberColumn = "number"
+
+// Recipient is one row of numbers.txt: a send target plus the template
+// variables declared in the CSV header. Number is usually a bare phone
+// number, but may also be a "group:"/"broadcast:" target or a raw JID -
+// see sender.jidFor for the targets it accepts.
+type Recipient struct {
+	Number string
+	Vars   map[string]string
+}
+
+// Load reads the CSV recipient file at path. The header row must contain a
+// "number" column; every other column is exposed to campaign templates
+// under its header name (e.g. a "Name" column becomes {{.Name}}).
+func Load(path string) ([]Recipient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.TrimLeadingSpace = true
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no rows found")
+	}
+
+	header := rows[0]
+	numberIdx := -1
+	for i, col := range header {
+		if strings.EqualFold(strings.TrimSpace(col), numberColumn) {
+			numberIdx = i
+			break
+		}
+	}
+	if numberIdx == -1 {
+		return nil, fmt.Errorf("header is missing required '%s' column", numberColumn)
+	}
+
+	var recipients []Recipient
+	for _, row := range rows[1:] {
+		number := strings.TrimSpace(row[numberIdx])
+		if number == "" {
+			continue
+		}
+
+		vars := make(map[string]string, len(header))
+		for i, col := range header {
+			if i == numberIdx || i >= len(row) {
+				continue
+			}
+			vars[strings.TrimSpace(col)] = strings.TrimSpace(row[i])
+		}
+		vars["Number"] = number
+
+		recipients = append(recipients, Recipient{Number: number, Vars: vars})
+	}
+
+	return recipients, nil
+}
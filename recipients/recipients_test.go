@@ -0,0 +1,50 @@
+package recipients
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCSV(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "numbers.txt")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write test CSV: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeCSV(t, "Number,Name\n15551234567,Ada\n,Skipped\n15557654321,Grace\n")
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Load returned %d recipients, want 2 (blank number row should be skipped)", len(got))
+	}
+	if got[0].Number != "15551234567" || got[0].Vars["Name"] != "Ada" || got[0].Vars["Number"] != "15551234567" {
+		t.Errorf("Load()[0] = %+v, unexpected", got[0])
+	}
+	if got[1].Number != "15557654321" || got[1].Vars["Name"] != "Grace" {
+		t.Errorf("Load()[1] = %+v, unexpected", got[1])
+	}
+}
+
+func TestLoadMissingNumberColumn(t *testing.T) {
+	path := writeCSV(t, "Name,Phone\nAda,15551234567\n")
+
+	if _, err := Load(path); err == nil {
+		t.Error("Load should error when the CSV has no 'number' column")
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	path := writeCSV(t, "")
+
+	if _, err := Load(path); err == nil {
+		t.Error("Load should error on an empty CSV")
+	}
+}
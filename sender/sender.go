@@ -0,0 +1,401 @@
+// Package sender wraps the whatsmeow multi-device WhatsApp protocol client,
+// giving the rest of the application a small synchronous API for pairing
+// once and sending text messages afterwards.
+package sender
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/mdp/qrterminal/v3"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// sessionDBPath is where the device's cryptographic session state is kept
+// between runs. Deleting this file forces a fresh QR pairing next launch.
+const sessionDBPath = "whatsapp_session.db"
+
+// Logger matches the console+file logging signature used throughout the
+// application, so this package never needs its own logging policy.
+type Logger func(format string, args ...interface{})
+
+// Client is a paired WhatsApp session. Create one with NewClient and reuse
+// it for every send; it keeps a single persistent connection open.
+type Client struct {
+	wa  *whatsmeow.Client
+	log Logger
+}
+
+// NewClient opens (or creates) the local session store and connects to
+// WhatsApp. If no device has been paired yet, it renders a QR code to the
+// terminal via qrterminal and blocks until it is scanned.
+func NewClient(log Logger) (*Client, error) {
+	c, err := newUnconnected(log)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.wa.Store.ID == nil {
+		if err := c.pair(); err != nil {
+			return nil, err
+		}
+	} else if err := c.wa.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect existing session: %w", err)
+	}
+
+	return c, nil
+}
+
+// NewUnpaired opens the local session store and, if a device is already
+// paired, connects to WhatsApp. Unlike NewClient it never blocks on a
+// terminal QR code: if no device is paired yet it returns immediately with
+// an unpaired Client, leaving pairing to a caller driving PairViaChannel
+// itself (the provisioning HTTP API's /login websocket does this).
+func NewUnpaired(log Logger) (*Client, error) {
+	c, err := newUnconnected(log)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.wa.Store.ID != nil {
+		if err := c.wa.Connect(); err != nil {
+			return nil, fmt.Errorf("failed to connect existing session: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// newUnconnected opens the local session store and wraps its device in a
+// whatsmeow client, without connecting.
+func newUnconnected(log Logger) (*Client, error) {
+	dbLog := waLog.Stdout("Database", "ERROR", false)
+	container, err := sqlstore.New(context.Background(), "sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", sessionDBPath), dbLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store '%s': %w", sessionDBPath, err)
+	}
+
+	deviceStore, err := container.GetFirstDevice(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load device from session store: %w", err)
+	}
+
+	clientLog := waLog.Stdout("Client", "ERROR", false)
+	return &Client{wa: whatsmeow.NewClient(deviceStore, clientLog), log: log}, nil
+}
+
+// pair performs first-time QR login: it prints a scannable QR code to the
+// terminal and waits for the phone to confirm pairing before returning.
+func (c *Client) pair() error {
+	qrChan, _ := c.wa.GetQRChannel(context.Background())
+	if err := c.wa.Connect(); err != nil {
+		return fmt.Errorf("failed to connect for QR pairing: %w", err)
+	}
+
+	for evt := range qrChan {
+		switch evt.Event {
+		case "code":
+			c.log("Scan this QR code with WhatsApp on your phone (Linked Devices):\n")
+			qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
+		case "success":
+			c.log("Successfully paired with WhatsApp.\n")
+		default:
+			c.log("QR login event: %s\n", evt.Event)
+		}
+	}
+
+	if c.wa.Store.ID == nil {
+		return fmt.Errorf("QR pairing did not complete")
+	}
+	return nil
+}
+
+// PairViaChannel starts connecting and returns the stream of whatsmeow QR
+// events for a caller to render itself, instead of printing to the
+// terminal. It returns a nil channel if a device is already paired. Used by
+// the provisioning HTTP API's /login websocket.
+func (c *Client) PairViaChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error) {
+	if c.wa.Store.ID != nil {
+		return nil, nil
+	}
+
+	qrChan, err := c.wa.GetQRChannel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open QR channel: %w", err)
+	}
+	if err := c.wa.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect for QR pairing: %w", err)
+	}
+	return qrChan, nil
+}
+
+// Close disconnects the underlying protocol client.
+func (c *Client) Close() {
+	c.wa.Disconnect()
+}
+
+// JID returns the logged-in account's JID, or types.EmptyJID if no device
+// is paired yet.
+func (c *Client) JID() types.JID {
+	if c.wa.Store.ID == nil {
+		return types.EmptyJID
+	}
+	return *c.wa.Store.ID
+}
+
+// IsConnected reports whether the underlying connection is currently up.
+func (c *Client) IsConnected() bool {
+	return c.wa.IsConnected()
+}
+
+// jidFor resolves a numbers.txt target to a WhatsApp JID. A bare
+// international phone number (digits only, no "+") targets that user. A
+// "group:<creator>-<timestamp>" or "broadcast:<timestamp>" target - or the
+// equivalent raw "...@g.us"/"...@broadcast" JID - targets a group chat or
+// broadcast list respectively.
+func jidFor(target string) (types.JID, error) {
+	if strings.Contains(target, "@") {
+		return types.ParseJID(target)
+	}
+	switch {
+	case strings.HasPrefix(target, "group:"):
+		return types.NewJID(strings.TrimPrefix(target, "group:"), types.GroupServer), nil
+	case strings.HasPrefix(target, "broadcast:"):
+		return types.NewJID(strings.TrimPrefix(target, "broadcast:"), types.BroadcastServer), nil
+	default:
+		return types.NewJID(target, types.DefaultUserServer), nil
+	}
+}
+
+// SendText sends a plain text message to target (a phone number, or a
+// "group:"/"broadcast:" target - see jidFor) and returns the
+// server-assigned message ID.
+func (c *Client) SendText(target, body string) (string, error) {
+	jid, err := jidFor(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+
+	resp, err := c.wa.SendMessage(context.Background(), jid, &waProto.Message{
+		Conversation: proto.String(body),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send message to %s: %w", target, err)
+	}
+	return resp.ID, nil
+}
+
+// upload reads path and uploads it to WhatsApp's media servers, returning
+// the fields needed to reference it from a message.
+func (c *Client) upload(path string, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return whatsmeow.UploadResponse{}, nil, fmt.Errorf("failed to read attachment '%s': %w", path, err)
+	}
+
+	uploaded, err := c.wa.Upload(context.Background(), data, mediaType)
+	if err != nil {
+		return whatsmeow.UploadResponse{}, nil, fmt.Errorf("failed to upload attachment '%s': %w", path, err)
+	}
+	return uploaded, data, nil
+}
+
+// SendImage uploads the image at path and sends it to target with an
+// optional caption.
+func (c *Client) SendImage(target, path, caption string) (string, error) {
+	jid, err := jidFor(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+	uploaded, data, err := c.upload(path, whatsmeow.MediaImage)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.wa.SendMessage(context.Background(), jid, &waProto.Message{
+		ImageMessage: &waProto.ImageMessage{
+			Caption:       proto.String(caption),
+			Mimetype:      proto.String(http.DetectContentType(data)),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send image to %s: %w", target, err)
+	}
+	return resp.ID, nil
+}
+
+// SendVideo uploads the video at path and sends it to target with an
+// optional caption.
+func (c *Client) SendVideo(target, path, caption string) (string, error) {
+	jid, err := jidFor(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+	uploaded, data, err := c.upload(path, whatsmeow.MediaVideo)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.wa.SendMessage(context.Background(), jid, &waProto.Message{
+		VideoMessage: &waProto.VideoMessage{
+			Caption:       proto.String(caption),
+			Mimetype:      proto.String(http.DetectContentType(data)),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send video to %s: %w", target, err)
+	}
+	return resp.ID, nil
+}
+
+// SendDocument uploads the file at path and sends it to target as a
+// document attachment, displayed as filename (or path's base name if
+// filename is empty) with an optional caption.
+func (c *Client) SendDocument(target, path, filename, caption string) (string, error) {
+	jid, err := jidFor(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+	uploaded, data, err := c.upload(path, whatsmeow.MediaDocument)
+	if err != nil {
+		return "", err
+	}
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+
+	resp, err := c.wa.SendMessage(context.Background(), jid, &waProto.Message{
+		DocumentMessage: &waProto.DocumentMessage{
+			Title:         proto.String(filename),
+			FileName:      proto.String(filename),
+			Caption:       proto.String(caption),
+			Mimetype:      proto.String(http.DetectContentType(data)),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send document to %s: %w", target, err)
+	}
+	return resp.ID, nil
+}
+
+// SendDocumentFromURL downloads mediaURL and sends it to target as a
+// document attachment with an optional caption. This is how the HTTP
+// provisioning API attaches media, since API callers pass a URL rather
+// than a local path.
+func (c *Client) SendDocumentFromURL(target, mediaURL, filename, caption string) (string, error) {
+	jid, err := jidFor(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+
+	httpResp, err := http.Get(mediaURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch media '%s': %w", mediaURL, err)
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read media '%s': %w", mediaURL, err)
+	}
+	if filename == "" {
+		filename = filepath.Base(mediaURL)
+	}
+
+	uploaded, err := c.wa.Upload(context.Background(), data, whatsmeow.MediaDocument)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media '%s': %w", mediaURL, err)
+	}
+
+	resp, err := c.wa.SendMessage(context.Background(), jid, &waProto.Message{
+		DocumentMessage: &waProto.DocumentMessage{
+			Title:         proto.String(filename),
+			FileName:      proto.String(filename),
+			Caption:       proto.String(caption),
+			Mimetype:      proto.String(http.DetectContentType(data)),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send media to %s: %w", target, err)
+	}
+	return resp.ID, nil
+}
+
+// SendContact sends a vCard contact card to target, displayed under
+// displayName.
+func (c *Client) SendContact(target, displayName, vcard string) (string, error) {
+	jid, err := jidFor(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %s: %w", target, err)
+	}
+
+	resp, err := c.wa.SendMessage(context.Background(), jid, &waProto.Message{
+		ContactMessage: &waProto.ContactMessage{
+			DisplayName: proto.String(displayName),
+			Vcard:       proto.String(vcard),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send contact to %s: %w", target, err)
+	}
+	return resp.ID, nil
+}
+
+// GroupInfo is a joined group's JID and display name, as surfaced by
+// --list-groups.
+type GroupInfo struct {
+	JID  string
+	Name string
+}
+
+// ListGroups returns every group chat the logged-in account has joined.
+// WhatsApp's protocol has no equivalent discovery call for broadcast lists,
+// so those must still be targeted manually with a "broadcast:" prefix in
+// numbers.txt.
+func (c *Client) ListGroups() ([]GroupInfo, error) {
+	groups, err := c.wa.GetJoinedGroups(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list joined groups: %w", err)
+	}
+
+	infos := make([]GroupInfo, 0, len(groups))
+	for _, g := range groups {
+		infos = append(infos, GroupInfo{JID: g.JID.String(), Name: g.Name})
+	}
+	return infos, nil
+}
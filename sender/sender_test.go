@@ -0,0 +1,39 @@
+package sender
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestJidFor(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		want   types.JID
+	}{
+		{name: "plain number", target: "15551234567", want: types.NewJID("15551234567", types.DefaultUserServer)},
+		{name: "group prefix", target: "group:12345-67890", want: types.NewJID("12345-67890", types.GroupServer)},
+		{name: "broadcast prefix", target: "broadcast:12345", want: types.NewJID("12345", types.BroadcastServer)},
+		{name: "raw user JID", target: "15551234567@s.whatsapp.net", want: types.NewJID("15551234567", types.DefaultUserServer)},
+		{name: "raw group JID", target: "12345-67890@g.us", want: types.NewJID("12345-67890", types.GroupServer)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := jidFor(c.target)
+			if err != nil {
+				t.Fatalf("jidFor(%q) returned error: %v", c.target, err)
+			}
+			if got != c.want {
+				t.Errorf("jidFor(%q) = %v, want %v", c.target, got, c.want)
+			}
+		})
+	}
+}
+
+func TestJidForInvalidRawJID(t *testing.T) {
+	if _, err := jidFor("15551234567.bad:1@s.whatsapp.net"); err == nil {
+		t.Error("jidFor should error on a malformed raw JID")
+	}
+}